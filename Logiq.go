@@ -2,109 +2,15 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	// "strconv"
-	"sync"
 	"time"
 )
 
-// Cache is a struct that represents an in-memory cache.
-type Cache struct {
-	mutex      sync.Mutex
-	cache      map[string]cacheValue
-	capacity   int
-	defaultTTL time.Duration
-}
-
-type cacheValue struct {
-	value     interface{}
-	expiry    time.Time
-	permanent bool
-}
-
-// NewCache creates a new Cache with the specified capacity and default TTL.
-func NewCache(capacity int, defaultTTL time.Duration) *Cache {
-	return &Cache{
-		cache:      make(map[string]cacheValue),
-		capacity:   capacity,
-		defaultTTL: defaultTTL,
-	}
-}
-
-// Set adds a new key-value pair to the Cache.
-func (c *Cache) Set(key string, value interface{}, ttl ...time.Duration) error {
-	var expiry time.Time
-	if len(ttl) == 0 {
-		expiry = time.Now().Add(c.defaultTTL)
-	} else {
-		expiry = time.Now().Add(ttl[0])
-	}
-
-	if c.capacity > 0 && len(c.cache) >= c.capacity {
-		c.evictOldest()
-	}
-
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	c.cache[key] = cacheValue{
-		value:  value,
-		expiry: expiry,
-	}
-
-	return nil
-}
-
-// Get retrieves the value associated with the specified key from the Cache.
-func (c *Cache) Get(key string) (interface{}, error) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	if value, ok := c.cache[key]; ok {
-		if value.expiry.Before(time.Now()) && !value.permanent {
-			delete(c.cache, key)
-			return nil, errors.New("key not found")
-		}
-		return value.value, nil
-	}
-
-	return nil, errors.New("key not found")
-}
-
-// Delete removes the specified key-value pair from the Cache.
-func (c *Cache) Delete(key string) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	if _, ok := c.cache[key]; ok {
-		delete(c.cache, key)
-		return nil
-	}
-
-	return fmt.Errorf("key %q not found", key)
-}
-
-// evictOldest removes the oldest key-value pair from the Cache.
-func (c *Cache) evictOldest() {
-	var oldestKey string
-	var oldestExpiry time.Time
-
-	for key, value := range c.cache {
-		if oldestExpiry.IsZero() || value.expiry.Before(oldestExpiry) {
-			oldestKey = key
-			oldestExpiry = value.expiry
-		}
-	}
-
-	delete(c.cache, oldestKey)
-}
-
 // Server is a struct that represents the HTTP server that serves the Cache.
 type Server struct {
-	cache *Cache
+	cache CacheInterface
 }
 
 // SetHandler handles requests to add a new key-value pair to the Cache.
@@ -124,14 +30,14 @@ if ttlStr != "" {
 	var err error
 	ttl, err = time.ParseDuration(ttlStr)
 	if err != nil {
-	http.Error(w, "invalid ttl", http.StatusBadRequest)
+	writeCacheError(w, errInvalidTTL(ttlStr))
 	return
 	}
 	}
 
 	err := s.cache.Set(key, value, ttl)
 if err != nil {
-	http.Error(w, err.Error(), http.StatusInternalServerError)
+	writeCacheError(w, err)
 	return
 }
 
@@ -148,7 +54,7 @@ if key == "" {
 
 value, err := s.cache.Get(key)
 if err != nil {
-	http.Error(w, err.Error(), http.StatusNotFound)
+	writeCacheError(w, err)
 	return
 }
 
@@ -171,21 +77,160 @@ if key == "" {
 	return
 }
 
-err := s.cache.Delete(key)
+err := s.cache.Del(key)
 if err != nil {
-	http.Error(w, err.Error(), http.StatusNotFound)
+	writeCacheError(w, err)
 	return
 }
 
 w.WriteHeader(http.StatusNoContent)
 }
 
+// keysResponse is the JSON body returned by KeysHandler.
+type keysResponse struct {
+	Keys []string `json:"keys"`
+	Size int      `json:"size"`
+}
+
+// KeysHandler handles requests to list every non-expired key in the Cache.
+func (s *Server) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys := s.cache.Keys()
+
+	resp := keysResponse{Keys: keys, Size: len(keys)}
+	jsonValue, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
+
+// statsResponse is the JSON body returned by StatsHandler.
+type statsResponse struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// StatsHandler handles requests for running cache hit/miss/eviction counters.
+func (s *Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	hits, misses, evictions := s.cache.Stats()
+
+	resp := statsResponse{Hits: hits, Misses: misses, Evictions: evictions}
+	jsonValue, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
+
+// MGetHandler handles requests to retrieve multiple keys in one round trip.
+func (s *Server) MGetHandler(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	values := s.cache.MGet(keys)
+
+	jsonValue, err := json.Marshal(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
+
+// MSetHandler handles requests to store multiple key/value pairs in one
+// round trip, all sharing the same optional TTL.
+func (s *Server) MSetHandler(w http.ResponseWriter, r *http.Request) {
+	ttlStr := r.URL.Query().Get("ttl")
+
+	var entries map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if ttlStr != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlStr)
+		if err != nil {
+			writeCacheError(w, errInvalidTTL(ttlStr))
+			return
+		}
+	}
+
+	if err := s.cache.MSet(entries, ttl); err != nil {
+		writeCacheError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// casRequest is the JSON body expected by CASHandler.
+type casRequest struct {
+	Key       string      `json:"key"`
+	PrevValue interface{} `json:"prevValue"`
+	NewValue  interface{} `json:"newValue"`
+	TTL       string      `json:"ttl,omitempty"`
+}
+
+// CASHandler handles requests to conditionally update a key only if its
+// current value matches the one the client last observed.
+func (s *Server) CASHandler(w http.ResponseWriter, r *http.Request) {
+	var req casRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil {
+			writeCacheError(w, errInvalidTTL(req.TTL))
+			return
+		}
+	}
+
+	if err := s.cache.CAS(req.Key, req.PrevValue, req.NewValue, ttl); err != nil {
+		writeCacheError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func main() {
-cache := NewCache(10, 5*time.Minute)
+cache, err := NewCache("lru://?capacity=10", 5*time.Minute)
+if err != nil {
+	log.Fatal(err)
+}
 server := &Server{cache}
 http.HandleFunc("/set", server.SetHandler)
 http.HandleFunc("/get", server.GetHandler)
 http.HandleFunc("/delete", server.DeleteHandler)
+http.HandleFunc("/keys", server.KeysHandler)
+http.HandleFunc("/stats", server.StatsHandler)
+http.HandleFunc("/mget", server.MGetHandler)
+http.HandleFunc("/mset", server.MSetHandler)
+http.HandleFunc("/cas", server.CASHandler)
 
 log.Fatal(http.ListenAndServe(":8080", nil))
 
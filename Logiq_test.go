@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	cache, err := NewCache("memory://", time.Minute)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	t.Cleanup(cache.Stop)
+	return &Server{cache: cache}
+}
+
+func TestKeysHandler(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cache.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.KeysHandler(rec, httptest.NewRequest(http.MethodGet, "/keys", nil))
+
+	var resp keysResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Size != 1 || len(resp.Keys) != 1 || resp.Keys[0] != "a" {
+		t.Fatalf("KeysHandler response = %+v, want one key \"a\"", resp)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cache.Set("a", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	s.cache.Get("a")
+	s.cache.Get("missing")
+
+	rec := httptest.NewRecorder()
+	s.StatsHandler(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	var resp statsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Hits != 1 || resp.Misses != 1 {
+		t.Fatalf("StatsHandler response = %+v, want 1 hit and 1 miss", resp)
+	}
+}
+
+func TestMSetAndMGetHandlers(t *testing.T) {
+	s := newTestServer(t)
+
+	body, err := json.Marshal(map[string]interface{}{"a": "1", "b": "2"})
+	if err != nil {
+		t.Fatalf("marshal mset body: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.MSetHandler(rec, httptest.NewRequest(http.MethodPost, "/mset", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("MSetHandler status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	keys, err := json.Marshal([]string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatalf("marshal mget body: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	s.MGetHandler(rec, httptest.NewRequest(http.MethodPost, "/mget", bytes.NewReader(keys)))
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &values); err != nil {
+		t.Fatalf("unmarshal mget response: %v", err)
+	}
+	if values["a"] != "1" || values["b"] != "2" || len(values) != 2 {
+		t.Fatalf("MGetHandler response = %+v, want a=1, b=2 only", values)
+	}
+}
+
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// CacheInterface is the contract implemented by every cache backend
+// driver. Server and handler code depends only on this interface, never
+// on a concrete driver, so new backends can be added without touching
+// callers.
+type CacheInterface interface {
+	// Has reports whether key is present and not expired.
+	Has(key string) bool
+	// Get retrieves the value stored under key. If dst is provided, the
+	// value is additionally assigned into it, letting callers receive a
+	// typed result without a type assertion.
+	Get(key string, dst ...interface{}) (interface{}, error)
+	// Set stores value under key, optionally overriding the driver's
+	// default TTL. A ttl of zero or less means the entry never expires.
+	Set(key string, value interface{}, ttl ...time.Duration) error
+	// Del removes the entry stored under key.
+	Del(key string) error
+	// Keys returns all non-expired keys currently held by the driver.
+	Keys() []string
+	// Len returns the number of non-expired entries currently held.
+	Len() int
+	// Stats returns running totals of cache hits, misses, and evictions.
+	Stats() (hits, misses, evictions uint64)
+	// MGet retrieves multiple keys in one call. Missing or expired keys
+	// are simply absent from the result.
+	MGet(keys []string) map[string]interface{}
+	// MSet stores multiple key/value pairs in one call, all sharing the
+	// same TTL. A ttl of zero or less means the entries never expire.
+	MSet(entries map[string]interface{}, ttl ...time.Duration) error
+	// CAS stores newValue under key only if the current value equals
+	// prevValue (nil prevValue means "key must not currently exist").
+	// It returns ErrCASMismatch if the comparison fails.
+	CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error
+	// Stop terminates any background goroutines or connections owned by
+	// the driver (e.g. a TTL sweeper). It is safe to call more than once.
+	Stop()
+}
+
+// EvictReason identifies why an entry left the cache.
+type EvictReason int
+
+const (
+	EvictReasonExpired EvictReason = iota
+	EvictReasonCapacity
+	EvictReasonDeleted
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// OnEvictFunc is called whenever an entry leaves the cache, whether by
+// TTL expiry, capacity eviction, or explicit deletion.
+type OnEvictFunc func(key string, value interface{}, reason EvictReason)
+
+// EvictNotifier is implemented by drivers that can notify callers when
+// entries are evicted. Only the in-process drivers (memory, lru)
+// implement it; remote drivers rely on their own server-side eviction.
+type EvictNotifier interface {
+	OnEvict(fn OnEvictFunc)
+}
+
+// Increment atomically adds delta to the numeric value stored at key,
+// retrying through CAS until it wins or hits a non-mismatch error, and
+// returns the resulting value. A missing key is treated as zero.
+func Increment(cache CacheInterface, key string, delta int64, ttl ...time.Duration) (int64, error) {
+	for {
+		current, err := cache.Get(key)
+		var n int64
+		switch {
+		case err != nil:
+			current = nil
+		default:
+			n, err = toInt64(current)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		next := n + delta
+		err = cache.CAS(key, current, next, ttl...)
+		if err == nil {
+			return next, nil
+		}
+		if errors.Is(err, ErrCASMismatch) {
+			continue
+		}
+		return 0, err
+	}
+}
+
+// Decrement atomically subtracts delta from the numeric value stored at
+// key. It is Increment with the sign flipped.
+func Decrement(cache CacheInterface, key string, delta int64, ttl ...time.Duration) (int64, error) {
+	return Increment(cache, key, -delta, ttl...)
+}
+
+// toInt64 coerces the numeric types that flow through JSON and gob
+// decoding into an int64, or reports that value isn't numeric.
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("value %v is not numeric", value)
+	}
+}
+
+// NewCache parses driverURL and constructs the matching CacheInterface
+// implementation. Supported schemes are:
+//
+//	memory://?capacity=1000  in-memory map, evicts soonest-to-expire over capacity
+//	lru://?capacity=1000     in-memory map with LRU eviction
+//	redis://host:port/db     Redis-backed driver
+//	memcache://host:port     Memcached-backed driver
+func NewCache(driverURL string, defaultTTL time.Duration) (CacheInterface, error) {
+	u, err := url.Parse(driverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse driver url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "memory", "":
+		capacity := 0
+		if c := u.Query().Get("capacity"); c != "" {
+			capacity, err = strconv.Atoi(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid memory capacity %q: %w", c, err)
+			}
+		}
+		sweep, err := parseDurationParam(u, "sweep", time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		return newMemoryDriver(capacity, defaultTTL, sweep), nil
+
+	case "lru":
+		capacity := 0
+		if c := u.Query().Get("capacity"); c != "" {
+			capacity, err = strconv.Atoi(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lru capacity %q: %w", c, err)
+			}
+		}
+		sweep, err := parseDurationParam(u, "sweep", time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		return newLRUDriver(capacity, defaultTTL, sweep), nil
+
+	case "redis":
+		return newRedisDriver(u, defaultTTL)
+
+	case "memcache", "memcached":
+		return newMemcacheDriver(u, defaultTTL)
+
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", u.Scheme)
+	}
+}
+
+// parseDurationParam parses the named query parameter of u as a
+// time.Duration, falling back to fallback if it is absent.
+func parseDurationParam(u *url.URL, name string, fallback time.Duration) (time.Duration, error) {
+	raw := u.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return d, nil
+}
+
+// resolveExpiry computes the expiry and permanence for a new or updated
+// entry given an optional ttl override, falling back to defaultTTL. A
+// ttl of zero or less (whether passed explicitly or via defaultTTL)
+// marks the entry permanent: it is exempt from expiry checks and is
+// restored as permanent across a persistence reload.
+func resolveExpiry(defaultTTL time.Duration, ttl ...time.Duration) (expiry time.Time, permanent bool) {
+	d := defaultTTL
+	if len(ttl) > 0 {
+		d = ttl[0]
+	}
+	if d <= 0 {
+		return time.Time{}, true
+	}
+	return time.Now().Add(d), false
+}
+
+func init() {
+	// Common concrete types that flow through the HTTP handlers. Gob
+	// requires every concrete type behind an interface{} to be
+	// registered before it can encode or decode one.
+	gob.Register("")
+	gob.Register(0)
+	gob.Register(int64(0))
+	gob.Register(float64(0))
+	gob.Register(false)
+	gob.Register([]byte(nil))
+	gob.Register(map[string]interface{}(nil))
+}
+
+// encodeValue serializes an arbitrary value into bytes for drivers that
+// store entries outside the process, such as Redis and Memcached.
+// In-process drivers keep values as interface{} and never call this.
+func encodeValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, fmt.Errorf("encode cache value: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue is the inverse of encodeValue. If dst is non-nil, the
+// decoded value is additionally copied into it, letting callers decode
+// straight into a typed variable instead of type-asserting the result.
+func decodeValue(data []byte, dst interface{}) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, fmt.Errorf("decode cache value: %w", err)
+	}
+
+	if dst != nil {
+		if err := assignTo(dst, value); err != nil {
+			return nil, fmt.Errorf("decode cache value into dst: %w", err)
+		}
+	}
+
+	return value, nil
+}
+
+// assignTo copies value into the variable pointed to by dst. It is used
+// by in-process drivers, which already hold the concrete value, and by
+// decodeValue once the stored bytes have been decoded.
+func assignTo(dst interface{}, value interface{}) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("dst must be a non-nil pointer")
+	}
+
+	vv := reflect.ValueOf(value)
+	if !vv.Type().AssignableTo(dv.Elem().Type()) {
+		return fmt.Errorf("cannot assign %T into %T", value, dst)
+	}
+
+	dv.Elem().Set(vv)
+	return nil
+}
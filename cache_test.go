@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCacheMemoryHonorsCapacityParam(t *testing.T) {
+	cache, err := NewCache("memory://?capacity=2", time.Minute)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	defer cache.Stop()
+
+	if err := cache.Set("a", 1, time.Minute); err != nil {
+		t.Fatalf("Set a: %v", err)
+	}
+	if err := cache.Set("b", 2, 2*time.Minute); err != nil {
+		t.Fatalf("Set b: %v", err)
+	}
+	if err := cache.Set("c", 3, 3*time.Minute); err != nil {
+		t.Fatalf("Set c: %v", err)
+	}
+
+	if got := cache.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2; NewCache did not honor the capacity query param", got)
+	}
+}
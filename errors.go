@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Error codes carried by CacheError. Handlers switch on these instead of
+// parsing error strings.
+const (
+	ErrCodeKeyNotFound      = 100
+	ErrCodeInvalidTTL       = 101
+	ErrCodeCapacityExceeded = 102
+	ErrCodeCASMismatch      = 103
+	ErrCodeExpired          = 104
+)
+
+// CacheError is the typed error returned by Cache operations. It carries
+// a stable Code a caller can switch on, a human-readable Message, and an
+// optional Cause (typically the offending key or input).
+type CacheError struct {
+	Code    int    `json:"errorCode"`
+	Message string `json:"message"`
+	Cause   string `json:"cause,omitempty"`
+}
+
+func (e *CacheError) Error() string {
+	if e.Cause == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Cause)
+}
+
+func newCacheError(code int, message, cause string) *CacheError {
+	return &CacheError{Code: code, Message: message, Cause: cause}
+}
+
+// ErrCASMismatch is returned by CAS when the stored value does not match
+// the expected previous value.
+var ErrCASMismatch = newCacheError(ErrCodeCASMismatch, "cas: value does not match", "")
+
+// errKeyNotFound builds a KeyNotFound CacheError carrying key as its cause.
+func errKeyNotFound(key string) *CacheError {
+	return newCacheError(ErrCodeKeyNotFound, "key not found", key)
+}
+
+// errExpired builds an Expired CacheError carrying key as its cause.
+func errExpired(key string) *CacheError {
+	return newCacheError(ErrCodeExpired, "key expired", key)
+}
+
+// errInvalidTTL builds an InvalidTTL CacheError carrying the offending
+// TTL string as its cause.
+func errInvalidTTL(raw string) *CacheError {
+	return newCacheError(ErrCodeInvalidTTL, "invalid ttl", raw)
+}
+
+// httpStatusFor maps a CacheError code to the HTTP status handlers
+// should respond with.
+func httpStatusFor(code int) int {
+	switch code {
+	case ErrCodeKeyNotFound, ErrCodeExpired:
+		return http.StatusNotFound
+	case ErrCodeInvalidTTL:
+		return http.StatusBadRequest
+	case ErrCodeCapacityExceeded:
+		return http.StatusInsufficientStorage
+	case ErrCodeCASMismatch:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeCacheError writes err to w as a JSON CacheError body, using the
+// status that matches its code. Errors that aren't a *CacheError are
+// wrapped as an unclassified 500.
+func writeCacheError(w http.ResponseWriter, err error) {
+	cacheErr, ok := err.(*CacheError)
+	if !ok {
+		cacheErr = newCacheError(0, err.Error(), "")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFor(cacheErr.Code))
+	json.NewEncoder(w).Encode(cacheErr)
+}
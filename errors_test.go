@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPStatusFor(t *testing.T) {
+	cases := []struct {
+		code int
+		want int
+	}{
+		{ErrCodeKeyNotFound, http.StatusNotFound},
+		{ErrCodeExpired, http.StatusNotFound},
+		{ErrCodeInvalidTTL, http.StatusBadRequest},
+		{ErrCodeCapacityExceeded, http.StatusInsufficientStorage},
+		{ErrCodeCASMismatch, http.StatusPreconditionFailed},
+		{0, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if got := httpStatusFor(c.code); got != c.want {
+			t.Errorf("httpStatusFor(%d) = %d, want %d", c.code, got, c.want)
+		}
+	}
+}
+
+func TestWriteCacheErrorUnclassifiedErrorMapsTo500(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeCacheError(rec, errors.New("boom"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d for a non-CacheError", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestCASHandlerMismatchReturnsPreconditionFailed(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.cache.Set("a", "v1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	body, err := json.Marshal(casRequest{Key: "a", PrevValue: "wrong", NewValue: "v2"})
+	if err != nil {
+		t.Fatalf("marshal cas body: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.CASHandler(rec, httptest.NewRequest(http.MethodPost, "/cas", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("CASHandler status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	var cacheErr CacheError
+	if err := json.Unmarshal(rec.Body.Bytes(), &cacheErr); err != nil {
+		t.Fatalf("unmarshal error body: %v", err)
+	}
+	if cacheErr.Code != ErrCodeCASMismatch {
+		t.Fatalf("error body code = %d, want %d", cacheErr.Code, ErrCodeCASMismatch)
+	}
+}
+
+func TestSetHandlerInvalidTTLReturnsBadRequest(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/set?key=a&value=1&ttl=notaduration", nil)
+	rec := httptest.NewRecorder()
+	s.SetHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("SetHandler status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var cacheErr CacheError
+	if err := json.Unmarshal(rec.Body.Bytes(), &cacheErr); err != nil {
+		t.Fatalf("unmarshal error body: %v", err)
+	}
+	if cacheErr.Code != ErrCodeInvalidTTL {
+		t.Fatalf("error body code = %d, want %d", cacheErr.Code, ErrCodeInvalidTTL)
+	}
+}
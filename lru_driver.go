@@ -0,0 +1,351 @@
+package main
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lruDriver is a capacity-bounded in-memory driver selected via the
+// "lru://?capacity=N" driver URL. Entries are kept in a doubly-linked
+// list ordered by recency of use: Get and Set move an entry to the
+// front, and eviction pops from the back, so both cost O(1) regardless
+// of how many entries the driver holds.
+type lruDriver struct {
+	mutex      sync.Mutex
+	list       *list.List
+	elements   map[string]*list.Element
+	capacity   int
+	defaultTTL time.Duration
+	onEvict    OnEvictFunc
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	stopOnce  sync.Once
+	sweepStop chan struct{}
+	sweepDone chan struct{}
+}
+
+// lruEntry is the value stored in each list element.
+type lruEntry struct {
+	key   string
+	value cacheValue
+}
+
+// newLRUDriver creates a capacity-bounded LRU driver and starts its
+// background TTL sweeper at sweepInterval.
+func newLRUDriver(capacity int, defaultTTL, sweepInterval time.Duration) *lruDriver {
+	d := &lruDriver{
+		list:       list.New(),
+		elements:   make(map[string]*list.Element),
+		capacity:   capacity,
+		defaultTTL: defaultTTL,
+		sweepStop:  make(chan struct{}),
+		sweepDone:  make(chan struct{}),
+	}
+	go d.sweepLoop(sweepInterval)
+	return d
+}
+
+// OnEvict registers fn to be called whenever an entry leaves the cache.
+func (d *lruDriver) OnEvict(fn OnEvictFunc) {
+	d.mutex.Lock()
+	d.onEvict = fn
+	d.mutex.Unlock()
+}
+
+func (d *lruDriver) notifyEvict(key string, value interface{}, reason EvictReason) {
+	if d.onEvict != nil {
+		d.onEvict(key, value, reason)
+	}
+}
+
+// sweepLoop periodically reclaims expired entries so they don't linger
+// and count against capacity until the next Get. Callers stop it via Stop.
+func (d *lruDriver) sweepLoop(interval time.Duration) {
+	defer close(d.sweepDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.sweep()
+		case <-d.sweepStop:
+			return
+		}
+	}
+}
+
+func (d *lruDriver) sweep() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	var next *list.Element
+	for e := d.list.Front(); e != nil; e = next {
+		next = e.Next()
+		entry := e.Value.(*lruEntry)
+		if !entry.value.permanent && entry.value.expiry.Before(now) {
+			d.removeElementLocked(e, EvictReasonExpired)
+		}
+	}
+}
+
+// Stop terminates the background TTL sweeper. It is safe to call more
+// than once.
+func (d *lruDriver) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.sweepStop)
+		<-d.sweepDone
+	})
+}
+
+func (d *lruDriver) Has(key string) bool {
+	_, err := d.Get(key)
+	return err == nil
+}
+
+func (d *lruDriver) Set(key string, value interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if elem, ok := d.elements[key]; ok {
+		elem.Value.(*lruEntry).value = cacheValue{value: value, expiry: expiry, permanent: permanent}
+		d.list.MoveToFront(elem)
+		return nil
+	}
+
+	if d.capacity > 0 && len(d.elements) >= d.capacity {
+		d.evictOldestLocked(EvictReasonCapacity)
+	}
+
+	elem := d.list.PushFront(&lruEntry{
+		key:   key,
+		value: cacheValue{value: value, expiry: expiry, permanent: permanent},
+	})
+	d.elements[key] = elem
+
+	return nil
+}
+
+func (d *lruDriver) Get(key string, dst ...interface{}) (interface{}, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	elem, ok := d.elements[key]
+	if !ok {
+		atomic.AddUint64(&d.misses, 1)
+		return nil, errKeyNotFound(key)
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if entry.value.expiry.Before(time.Now()) && !entry.value.permanent {
+		d.removeElementLocked(elem, EvictReasonExpired)
+		atomic.AddUint64(&d.misses, 1)
+		return nil, errExpired(key)
+	}
+
+	d.list.MoveToFront(elem)
+
+	if len(dst) > 0 && dst[0] != nil {
+		if err := assignTo(dst[0], entry.value.value); err != nil {
+			return nil, err
+		}
+	}
+
+	atomic.AddUint64(&d.hits, 1)
+	return entry.value.value, nil
+}
+
+func (d *lruDriver) Del(key string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	elem, ok := d.elements[key]
+	if !ok {
+		return errKeyNotFound(key)
+	}
+
+	d.removeElementLocked(elem, EvictReasonDeleted)
+	return nil
+}
+
+func (d *lruDriver) Keys() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(d.elements))
+	for e := d.list.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*lruEntry)
+		if entry.value.permanent || entry.value.expiry.After(now) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of non-expired entries currently held.
+func (d *lruDriver) Len() int {
+	return len(d.Keys())
+}
+
+// Stats returns running totals of cache hits, misses, and evictions.
+func (d *lruDriver) Stats() (hits, misses, evictions uint64) {
+	return atomic.LoadUint64(&d.hits), atomic.LoadUint64(&d.misses), atomic.LoadUint64(&d.evictions)
+}
+
+// MGet retrieves multiple keys under a single mutex acquisition, moving
+// each hit to the front of the recency list.
+func (d *lruDriver) MGet(keys []string) map[string]interface{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	result := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		elem, ok := d.elements[key]
+		if !ok {
+			atomic.AddUint64(&d.misses, 1)
+			continue
+		}
+		entry := elem.Value.(*lruEntry)
+		if entry.value.expiry.Before(now) && !entry.value.permanent {
+			d.removeElementLocked(elem, EvictReasonExpired)
+			atomic.AddUint64(&d.misses, 1)
+			continue
+		}
+		d.list.MoveToFront(elem)
+		atomic.AddUint64(&d.hits, 1)
+		result[key] = entry.value.value
+	}
+	return result
+}
+
+// MSet stores multiple key/value pairs under a single mutex acquisition.
+func (d *lruDriver) MSet(entries map[string]interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, value := range entries {
+		if elem, ok := d.elements[key]; ok {
+			elem.Value.(*lruEntry).value = cacheValue{value: value, expiry: expiry, permanent: permanent}
+			d.list.MoveToFront(elem)
+			continue
+		}
+		if d.capacity > 0 && len(d.elements) >= d.capacity {
+			d.evictOldestLocked(EvictReasonCapacity)
+		}
+		elem := d.list.PushFront(&lruEntry{key: key, value: cacheValue{value: value, expiry: expiry, permanent: permanent}})
+		d.elements[key] = elem
+	}
+	return nil
+}
+
+// CAS stores newValue under key only if the current value equals
+// prevValue, moving the entry to the front of the recency list.
+func (d *lruDriver) CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	elem, ok := d.elements[key]
+	var existing cacheValue
+	if ok {
+		existing = elem.Value.(*lruEntry).value
+		if existing.expiry.Before(time.Now()) && !existing.permanent {
+			d.removeElementLocked(elem, EvictReasonExpired)
+			ok = false
+		}
+	}
+
+	if !ok {
+		if prevValue != nil {
+			return ErrCASMismatch
+		}
+	} else if !reflect.DeepEqual(existing.value, prevValue) {
+		return ErrCASMismatch
+	}
+
+	if ok {
+		elem.Value.(*lruEntry).value = cacheValue{value: newValue, expiry: expiry, permanent: permanent}
+		d.list.MoveToFront(elem)
+		return nil
+	}
+
+	if d.capacity > 0 && len(d.elements) >= d.capacity {
+		d.evictOldestLocked(EvictReasonCapacity)
+	}
+	newElem := d.list.PushFront(&lruEntry{key: key, value: cacheValue{value: newValue, expiry: expiry, permanent: permanent}})
+	d.elements[key] = newElem
+	return nil
+}
+
+// snapshotEntries returns every entry currently held, for persistence.
+func (d *lruDriver) snapshotEntries() []persistedEntry {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entries := make([]persistedEntry, 0, len(d.elements))
+	for e := d.list.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*lruEntry)
+		entries = append(entries, persistedEntry{
+			Key:       entry.key,
+			Value:     entry.value.value,
+			Expiry:    entry.value.expiry,
+			Permanent: entry.value.permanent,
+		})
+	}
+	return entries
+}
+
+// loadEntries restores entries produced by a prior snapshotEntries call,
+// oldest first, so the resulting recency order matches the order saved.
+func (d *lruDriver) loadEntries(entries []persistedEntry) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		elem := d.list.PushFront(&lruEntry{
+			key: entry.Key,
+			value: cacheValue{
+				value:     entry.Value,
+				expiry:    entry.Expiry,
+				permanent: entry.Permanent,
+			},
+		})
+		d.elements[entry.Key] = elem
+	}
+}
+
+// evictOldestLocked pops the least-recently-used entry from the back of
+// the list. Callers must hold d.mutex.
+func (d *lruDriver) evictOldestLocked(reason EvictReason) {
+	elem := d.list.Back()
+	if elem == nil {
+		return
+	}
+	d.removeElementLocked(elem, reason)
+	atomic.AddUint64(&d.evictions, 1)
+}
+
+// removeElementLocked detaches elem from both the list and the index,
+// notifying any registered OnEvict callback. Callers must hold d.mutex.
+func (d *lruDriver) removeElementLocked(elem *list.Element, reason EvictReason) {
+	entry := elem.Value.(*lruEntry)
+	d.list.Remove(elem)
+	delete(d.elements, entry.key)
+	d.notifyEvict(entry.key, entry.value.value, reason)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLRUDriver(capacity int) *lruDriver {
+	return newLRUDriver(capacity, time.Minute, time.Hour)
+}
+
+func TestLRUDriverCAS(t *testing.T) {
+	d := newTestLRUDriver(0)
+	defer d.Stop()
+
+	if err := d.CAS("a", nil, "v1"); err != nil {
+		t.Fatalf("CAS create: %v", err)
+	}
+	if err := d.CAS("a", "wrong", "v2"); !errors.Is(err, ErrCASMismatch) {
+		t.Fatalf("CAS with wrong prevValue = %v, want ErrCASMismatch", err)
+	}
+	if err := d.CAS("a", "v1", "v2"); err != nil {
+		t.Fatalf("CAS update: %v", err)
+	}
+	got, err := d.Get("a")
+	if err != nil || got != "v2" {
+		t.Fatalf("Get after CAS = (%v, %v), want (v2, nil)", got, err)
+	}
+}
+
+func TestLRUDriverEvictsLeastRecentlyUsed(t *testing.T) {
+	d := newTestLRUDriver(2)
+	defer d.Stop()
+
+	if err := d.Set("a", 1); err != nil {
+		t.Fatalf("Set a: %v", err)
+	}
+	if err := d.Set("b", 2); err != nil {
+		t.Fatalf("Set b: %v", err)
+	}
+
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, err := d.Get("a"); err != nil {
+		t.Fatalf("Get a: %v", err)
+	}
+
+	if err := d.Set("c", 3); err != nil {
+		t.Fatalf("Set c: %v", err)
+	}
+
+	if d.Has("b") {
+		t.Fatalf("least-recently-used entry %q should have been evicted", "b")
+	}
+	if !d.Has("a") || !d.Has("c") {
+		t.Fatalf("recently-used entries should survive capacity eviction")
+	}
+	if _, _, evictions := d.Stats(); evictions != 1 {
+		t.Fatalf("evictions = %d, want 1", evictions)
+	}
+}
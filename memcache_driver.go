@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memcacheDriver stores entries in a Memcached server using the classic
+// text protocol over a single persistent connection. Values are
+// gob-encoded at the driver boundary since Memcached only understands
+// bytes.
+type memcacheDriver struct {
+	mutex      sync.Mutex
+	conn       net.Conn
+	reader     *bufio.Reader
+	defaultTTL time.Duration
+}
+
+// newMemcacheDriver dials the Memcached instance described by u, e.g.
+// memcache://host:11211.
+func newMemcacheDriver(u *url.URL, defaultTTL time.Duration) (*memcacheDriver, error) {
+	addr := u.Host
+	if addr == "" {
+		addr = "localhost:11211"
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial memcached at %s: %w", addr, err)
+	}
+
+	return &memcacheDriver{
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		defaultTTL: defaultTTL,
+	}, nil
+}
+
+// memcacheExptime converts a Go duration into the integer seconds the
+// Memcached text protocol expects for exptime. Memcached treats 0 as
+// "never expire", so any positive duration under a second is rounded up
+// to 1 rather than truncated to 0, which would silently turn a
+// sub-second TTL into a permanent entry.
+func memcacheExptime(expiry time.Duration) int {
+	if expiry <= 0 {
+		return 0
+	}
+	seconds := expiry / time.Second
+	if expiry%time.Second != 0 {
+		seconds++
+	}
+	return int(seconds)
+}
+
+func (d *memcacheDriver) Has(key string) bool {
+	_, err := d.Get(key)
+	return err == nil
+}
+
+func (d *memcacheDriver) Set(key string, value interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.setLocked(key, encoded, expiry)
+}
+
+// setLocked issues a SET command. Callers must hold d.mutex.
+func (d *memcacheDriver) setLocked(key string, encoded []byte, expiry time.Duration) error {
+	cmd := fmt.Sprintf("set %s 0 %d %d\r\n", key, memcacheExptime(expiry), len(encoded))
+	if _, err := d.conn.Write([]byte(cmd)); err != nil {
+		return fmt.Errorf("write memcached set: %w", err)
+	}
+	if _, err := d.conn.Write(append(encoded, '\r', '\n')); err != nil {
+		return fmt.Errorf("write memcached payload: %w", err)
+	}
+
+	line, err := d.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read memcached set reply: %w", err)
+	}
+	if !strings.HasPrefix(line, "STORED") {
+		return fmt.Errorf("memcached set failed: %s", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+func (d *memcacheDriver) Get(key string, dst ...interface{}) (interface{}, error) {
+	d.mutex.Lock()
+	data, err := d.getLocked(key)
+	d.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var outDst interface{}
+	if len(dst) > 0 {
+		outDst = dst[0]
+	}
+	return decodeValue(data, outDst)
+}
+
+// getLocked issues a GET command. Callers must hold d.mutex.
+func (d *memcacheDriver) getLocked(key string) ([]byte, error) {
+	if _, err := d.conn.Write([]byte(fmt.Sprintf("get %s\r\n", key))); err != nil {
+		return nil, fmt.Errorf("write memcached get: %w", err)
+	}
+
+	header, err := d.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read memcached get header: %w", err)
+	}
+	header = strings.TrimSpace(header)
+	if header == "END" {
+		return nil, errKeyNotFound(key)
+	}
+
+	var gotKey string
+	var flags, length int
+	if _, err := fmt.Sscanf(header, "VALUE %s %d %d", &gotKey, &flags, &length); err != nil {
+		return nil, fmt.Errorf("parse memcached get header %q: %w", header, err)
+	}
+
+	data := make([]byte, length+2)
+	if _, err := readFull(d.reader, data); err != nil {
+		return nil, fmt.Errorf("read memcached value: %w", err)
+	}
+
+	if _, err := d.reader.ReadString('\n'); err != nil {
+		return nil, fmt.Errorf("read memcached end marker: %w", err)
+	}
+
+	return data[:length], nil
+}
+
+func (d *memcacheDriver) Del(key string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, err := d.conn.Write([]byte(fmt.Sprintf("delete %s\r\n", key))); err != nil {
+		return fmt.Errorf("write memcached delete: %w", err)
+	}
+
+	line, err := d.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read memcached delete reply: %w", err)
+	}
+	if !strings.HasPrefix(line, "DELETED") {
+		return errKeyNotFound(key)
+	}
+	return nil
+}
+
+// CAS stores newValue under key only if the current value equals
+// prevValue, using Memcached's native gets/cas check-and-set commands.
+func (d *memcacheDriver) CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	encodedNew, err := encodeValue(newValue)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	data, casID, err := d.getsLocked(key)
+	if err != nil {
+		cacheErr, ok := err.(*CacheError)
+		if !ok || cacheErr.Code != ErrCodeKeyNotFound {
+			return err
+		}
+		if prevValue != nil {
+			return ErrCASMismatch
+		}
+		return d.setLocked(key, encodedNew, expiry)
+	}
+
+	current, err := decodeValue(data, nil)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(current, prevValue) {
+		return ErrCASMismatch
+	}
+
+	return d.casLocked(key, encodedNew, expiry, casID)
+}
+
+// getsLocked issues a GETS command, which additionally returns the
+// per-value CAS token needed by casLocked. Callers must hold d.mutex.
+func (d *memcacheDriver) getsLocked(key string) ([]byte, string, error) {
+	if _, err := d.conn.Write([]byte(fmt.Sprintf("gets %s\r\n", key))); err != nil {
+		return nil, "", fmt.Errorf("write memcached gets: %w", err)
+	}
+
+	header, err := d.reader.ReadString('\n')
+	if err != nil {
+		return nil, "", fmt.Errorf("read memcached gets header: %w", err)
+	}
+	header = strings.TrimSpace(header)
+	if header == "END" {
+		return nil, "", errKeyNotFound(key)
+	}
+
+	var gotKey, casID string
+	var flags, length int
+	if _, err := fmt.Sscanf(header, "VALUE %s %d %d %s", &gotKey, &flags, &length, &casID); err != nil {
+		return nil, "", fmt.Errorf("parse memcached gets header %q: %w", header, err)
+	}
+
+	data := make([]byte, length+2)
+	if _, err := readFull(d.reader, data); err != nil {
+		return nil, "", fmt.Errorf("read memcached value: %w", err)
+	}
+	if _, err := d.reader.ReadString('\n'); err != nil {
+		return nil, "", fmt.Errorf("read memcached end marker: %w", err)
+	}
+
+	return data[:length], casID, nil
+}
+
+// casLocked issues a CAS command using the token returned by
+// getsLocked. Callers must hold d.mutex.
+func (d *memcacheDriver) casLocked(key string, encoded []byte, expiry time.Duration, casID string) error {
+	cmd := fmt.Sprintf("cas %s 0 %d %d %s\r\n", key, memcacheExptime(expiry), len(encoded), casID)
+	if _, err := d.conn.Write([]byte(cmd)); err != nil {
+		return fmt.Errorf("write memcached cas: %w", err)
+	}
+	if _, err := d.conn.Write(append(encoded, '\r', '\n')); err != nil {
+		return fmt.Errorf("write memcached payload: %w", err)
+	}
+
+	line, err := d.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read memcached cas reply: %w", err)
+	}
+	switch {
+	case strings.HasPrefix(line, "STORED"):
+		return nil
+	case strings.HasPrefix(line, "EXISTS"), strings.HasPrefix(line, "NOT_FOUND"):
+		return ErrCASMismatch
+	default:
+		return fmt.Errorf("memcached cas failed: %s", strings.TrimSpace(line))
+	}
+}
+
+// Keys is not supported by the stock Memcached text protocol without the
+// lru_crawler admin commands, so it returns nil rather than guessing.
+func (d *memcacheDriver) Keys() []string {
+	return nil
+}
+
+// Len is not supported by the stock Memcached text protocol, so it
+// returns 0 rather than guessing.
+func (d *memcacheDriver) Len() int {
+	return 0
+}
+
+// Stats always returns zeros: hit/miss/eviction counters belong to the
+// Memcached server itself (see the "stats" command), not to this driver.
+func (d *memcacheDriver) Stats() (hits, misses, evictions uint64) {
+	return 0, 0, 0
+}
+
+// Stop closes the connection to Memcached. Eviction and expiry are
+// handled server-side, so there is no background sweeper to stop here.
+func (d *memcacheDriver) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.conn.Close()
+}
+
+// MGet retrieves multiple keys under a single mutex acquisition.
+func (d *memcacheDriver) MGet(keys []string) map[string]interface{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	result := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		data, err := d.getLocked(key)
+		if err != nil {
+			continue
+		}
+		value, err := decodeValue(data, nil)
+		if err != nil {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// MSet stores multiple key/value pairs under a single mutex acquisition.
+func (d *memcacheDriver) MSet(entries map[string]interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, value := range entries {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return err
+		}
+		if err := d.setLocked(key, encoded, expiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
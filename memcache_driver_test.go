@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemcacheExptime(t *testing.T) {
+	cases := []struct {
+		expiry time.Duration
+		want   int
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{500 * time.Millisecond, 1},
+		{time.Second, 1},
+		{90 * time.Second, 90},
+		{90*time.Second + 100*time.Millisecond, 91},
+	}
+
+	for _, c := range cases {
+		if got := memcacheExptime(c.expiry); got != c.want {
+			t.Errorf("memcacheExptime(%v) = %d, want %d", c.expiry, got, c.want)
+		}
+	}
+}
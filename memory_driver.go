@@ -0,0 +1,335 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// memoryDriver is the original in-memory cache backend: a mutex-guarded
+// map that evicts the soonest-to-expire entry once it is over capacity.
+// A background goroutine sweeps expired entries so they don't linger
+// and count against capacity between reads.
+type memoryDriver struct {
+	mutex      sync.Mutex
+	cache      map[string]cacheValue
+	capacity   int
+	defaultTTL time.Duration
+	onEvict    OnEvictFunc
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	stopOnce  sync.Once
+	sweepStop chan struct{}
+	sweepDone chan struct{}
+}
+
+type cacheValue struct {
+	value     interface{}
+	expiry    time.Time
+	permanent bool
+}
+
+// newMemoryDriver creates an in-memory driver bounded by capacity (0 for
+// unbounded) with the given default TTL, and starts its background TTL
+// sweeper at sweepInterval. Use the driver URL's "lru" scheme instead if
+// LRU-ordered, rather than soonest-to-expire, eviction is needed.
+func newMemoryDriver(capacity int, defaultTTL, sweepInterval time.Duration) *memoryDriver {
+	d := &memoryDriver{
+		cache:      make(map[string]cacheValue),
+		capacity:   capacity,
+		defaultTTL: defaultTTL,
+		sweepStop:  make(chan struct{}),
+		sweepDone:  make(chan struct{}),
+	}
+	go d.sweepLoop(sweepInterval)
+	return d
+}
+
+// OnEvict registers fn to be called whenever an entry leaves the cache.
+func (d *memoryDriver) OnEvict(fn OnEvictFunc) {
+	d.mutex.Lock()
+	d.onEvict = fn
+	d.mutex.Unlock()
+}
+
+func (d *memoryDriver) notifyEvict(key string, value interface{}, reason EvictReason) {
+	if d.onEvict != nil {
+		d.onEvict(key, value, reason)
+	}
+}
+
+// sweepLoop periodically reclaims expired entries so they don't linger
+// and count against capacity until the next Get. Callers stop it via Stop.
+func (d *memoryDriver) sweepLoop(interval time.Duration) {
+	defer close(d.sweepDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.sweep()
+		case <-d.sweepStop:
+			return
+		}
+	}
+}
+
+func (d *memoryDriver) sweep() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	for key, value := range d.cache {
+		if !value.permanent && value.expiry.Before(now) {
+			delete(d.cache, key)
+			d.notifyEvict(key, value.value, EvictReasonExpired)
+		}
+	}
+}
+
+// Stop terminates the background TTL sweeper. It is safe to call more
+// than once.
+func (d *memoryDriver) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.sweepStop)
+		<-d.sweepDone
+	})
+}
+
+func (d *memoryDriver) Has(key string) bool {
+	_, err := d.Get(key)
+	return err == nil
+}
+
+func (d *memoryDriver) Set(key string, value interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.capacity > 0 && len(d.cache) >= d.capacity {
+		if _, exists := d.cache[key]; !exists {
+			d.evictOldestLocked(EvictReasonCapacity)
+		}
+	}
+
+	d.cache[key] = cacheValue{
+		value:     value,
+		expiry:    expiry,
+		permanent: permanent,
+	}
+
+	return nil
+}
+
+func (d *memoryDriver) Get(key string, dst ...interface{}) (interface{}, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	value, ok := d.cache[key]
+	if !ok {
+		atomic.AddUint64(&d.misses, 1)
+		return nil, errKeyNotFound(key)
+	}
+	if value.expiry.Before(time.Now()) && !value.permanent {
+		delete(d.cache, key)
+		atomic.AddUint64(&d.misses, 1)
+		d.notifyEvict(key, value.value, EvictReasonExpired)
+		return nil, errExpired(key)
+	}
+
+	if len(dst) > 0 && dst[0] != nil {
+		if err := assignTo(dst[0], value.value); err != nil {
+			return nil, err
+		}
+	}
+
+	atomic.AddUint64(&d.hits, 1)
+	return value.value, nil
+}
+
+func (d *memoryDriver) Del(key string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	value, ok := d.cache[key]
+	if !ok {
+		return errKeyNotFound(key)
+	}
+
+	delete(d.cache, key)
+	d.notifyEvict(key, value.value, EvictReasonDeleted)
+	return nil
+}
+
+func (d *memoryDriver) Keys() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(d.cache))
+	for key, value := range d.cache {
+		if value.permanent || value.expiry.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of non-expired entries currently held.
+func (d *memoryDriver) Len() int {
+	return len(d.Keys())
+}
+
+// Stats returns running totals of cache hits, misses, and evictions.
+func (d *memoryDriver) Stats() (hits, misses, evictions uint64) {
+	return atomic.LoadUint64(&d.hits), atomic.LoadUint64(&d.misses), atomic.LoadUint64(&d.evictions)
+}
+
+// MGet retrieves multiple keys under a single mutex acquisition.
+func (d *memoryDriver) MGet(keys []string) map[string]interface{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	result := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := d.cache[key]
+		if !ok {
+			atomic.AddUint64(&d.misses, 1)
+			continue
+		}
+		if value.expiry.Before(now) && !value.permanent {
+			delete(d.cache, key)
+			atomic.AddUint64(&d.misses, 1)
+			d.notifyEvict(key, value.value, EvictReasonExpired)
+			continue
+		}
+		atomic.AddUint64(&d.hits, 1)
+		result[key] = value.value
+	}
+	return result
+}
+
+// MSet stores multiple key/value pairs under a single mutex acquisition.
+func (d *memoryDriver) MSet(entries map[string]interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, value := range entries {
+		if d.capacity > 0 && len(d.cache) >= d.capacity {
+			if _, exists := d.cache[key]; !exists {
+				d.evictOldestLocked(EvictReasonCapacity)
+			}
+		}
+		d.cache[key] = cacheValue{value: value, expiry: expiry, permanent: permanent}
+	}
+	return nil
+}
+
+// CAS stores newValue under key only if the current value equals
+// prevValue.
+func (d *memoryDriver) CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error {
+	expiry, permanent := resolveExpiry(d.defaultTTL, ttl...)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	existing, ok := d.cache[key]
+	if ok && existing.expiry.Before(time.Now()) && !existing.permanent {
+		delete(d.cache, key)
+		d.notifyEvict(key, existing.value, EvictReasonExpired)
+		ok = false
+	}
+
+	if !ok {
+		if prevValue != nil {
+			return ErrCASMismatch
+		}
+	} else if !reflect.DeepEqual(existing.value, prevValue) {
+		return ErrCASMismatch
+	}
+
+	if !ok && d.capacity > 0 && len(d.cache) >= d.capacity {
+		d.evictOldestLocked(EvictReasonCapacity)
+	}
+
+	d.cache[key] = cacheValue{value: newValue, expiry: expiry, permanent: permanent}
+	return nil
+}
+
+// snapshotEntries returns every entry currently held, for persistence.
+func (d *memoryDriver) snapshotEntries() []persistedEntry {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entries := make([]persistedEntry, 0, len(d.cache))
+	for key, value := range d.cache {
+		entries = append(entries, persistedEntry{
+			Key:       key,
+			Value:     value.value,
+			Expiry:    value.expiry,
+			Permanent: value.permanent,
+		})
+	}
+	return entries
+}
+
+// loadEntries restores entries produced by a prior snapshotEntries call.
+func (d *memoryDriver) loadEntries(entries []persistedEntry) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, entry := range entries {
+		d.cache[entry.Key] = cacheValue{
+			value:     entry.Value,
+			expiry:    entry.Expiry,
+			permanent: entry.Permanent,
+		}
+	}
+}
+
+// evictOldestLocked removes the soonest-to-expire entry, skipping
+// permanent entries since they have no meaningful expiry to compare. If
+// every entry is permanent, an arbitrary one is evicted anyway so
+// capacity is still enforced. Callers must hold d.mutex.
+func (d *memoryDriver) evictOldestLocked(reason EvictReason) {
+	var oldestKey string
+	var oldestExpiry time.Time
+	found := false
+
+	for key, value := range d.cache {
+		if value.permanent {
+			continue
+		}
+		if !found || value.expiry.Before(oldestExpiry) {
+			oldestKey = key
+			oldestExpiry = value.expiry
+			found = true
+		}
+	}
+
+	if !found {
+		for key := range d.cache {
+			oldestKey = key
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+
+	value := d.cache[oldestKey]
+	delete(d.cache, oldestKey)
+	atomic.AddUint64(&d.evictions, 1)
+	d.notifyEvict(oldestKey, value.value, reason)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestMemoryDriver(capacity int) *memoryDriver {
+	return newMemoryDriver(capacity, time.Minute, time.Hour)
+}
+
+func TestMemoryDriverCAS(t *testing.T) {
+	d := newTestMemoryDriver(0)
+	defer d.Stop()
+
+	if err := d.CAS("a", nil, "v1"); err != nil {
+		t.Fatalf("CAS create: %v", err)
+	}
+	if err := d.CAS("a", "wrong", "v2"); !errors.Is(err, ErrCASMismatch) {
+		t.Fatalf("CAS with wrong prevValue = %v, want ErrCASMismatch", err)
+	}
+	if err := d.CAS("a", "v1", "v2"); err != nil {
+		t.Fatalf("CAS update: %v", err)
+	}
+	got, err := d.Get("a")
+	if err != nil || got != "v2" {
+		t.Fatalf("Get after CAS = (%v, %v), want (v2, nil)", got, err)
+	}
+	if err := d.CAS("b", "anything", "v3"); !errors.Is(err, ErrCASMismatch) {
+		t.Fatalf("CAS on missing key with non-nil prevValue = %v, want ErrCASMismatch", err)
+	}
+}
+
+func TestMemoryDriverCapacityEviction(t *testing.T) {
+	d := newTestMemoryDriver(2)
+	defer d.Stop()
+
+	if err := d.Set("a", 1, time.Minute); err != nil {
+		t.Fatalf("Set a: %v", err)
+	}
+	if err := d.Set("b", 2, 2*time.Minute); err != nil {
+		t.Fatalf("Set b: %v", err)
+	}
+	if err := d.Set("c", 3, 3*time.Minute); err != nil {
+		t.Fatalf("Set c: %v", err)
+	}
+
+	if d.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2 after exceeding capacity", d.Len())
+	}
+	if d.Has("a") {
+		t.Fatalf("soonest-to-expire entry %q should have been evicted", "a")
+	}
+	if !d.Has("b") || !d.Has("c") {
+		t.Fatalf("later-expiring entries should survive capacity eviction")
+	}
+	if _, _, evictions := d.Stats(); evictions != 1 {
+		t.Fatalf("evictions = %d, want 1", evictions)
+	}
+}
+
+func TestMemoryDriverOnEvict(t *testing.T) {
+	d := newTestMemoryDriver(0)
+	defer d.Stop()
+
+	var gotKey string
+	var gotReason EvictReason
+	d.OnEvict(func(key string, value interface{}, reason EvictReason) {
+		gotKey, gotReason = key, reason
+	})
+
+	if err := d.Set("a", 1, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := d.Del("a"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if gotKey != "a" || gotReason != EvictReasonDeleted {
+		t.Fatalf("OnEvict callback got (%q, %v), want (\"a\", EvictReasonDeleted)", gotKey, gotReason)
+	}
+}
+
+func TestMemoryDriverSweepExpiresEntries(t *testing.T) {
+	d := newMemoryDriver(0, time.Minute, 20*time.Millisecond)
+	defer d.Stop()
+
+	evicted := make(chan struct {
+		key    string
+		reason EvictReason
+	}, 1)
+	d.OnEvict(func(key string, value interface{}, reason EvictReason) {
+		evicted <- struct {
+			key    string
+			reason EvictReason
+		}{key, reason}
+	})
+
+	if err := d.Set("a", 1, 10*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	select {
+	case got := <-evicted:
+		if got.key != "a" || got.reason != EvictReasonExpired {
+			t.Fatalf("sweep fired OnEvict with (%q, %v), want (\"a\", EvictReasonExpired)", got.key, got.reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sweep did not evict the expired entry in time")
+	}
+
+	if d.Has("a") {
+		t.Fatal("sweep should have removed the expired entry")
+	}
+}
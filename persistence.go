@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// persistedEntry is the on-disk representation of one cache entry.
+type persistedEntry struct {
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	Expiry    time.Time   `json:"expiry"`
+	Permanent bool        `json:"permanent"`
+}
+
+// snapshottable is implemented by in-process drivers that can expose
+// their raw entries for persistence. Remote drivers (Redis, Memcached)
+// manage their own durability and do not implement it.
+type snapshottable interface {
+	snapshotEntries() []persistedEntry
+	loadEntries(entries []persistedEntry)
+}
+
+// persistentCache wraps a snapshottable driver with write-through disk
+// persistence: every Set/Del marks the cache dirty, and a background
+// goroutine flushes the full snapshot to disk on flushInterval.
+type persistentCache struct {
+	CacheInterface
+	driver        snapshottable
+	path          string
+	flushInterval time.Duration
+
+	mutex sync.Mutex
+	dirty bool
+
+	stop chan struct{}
+	done chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewCacheWithPersistence builds a driver from driverURL, reloads it from
+// path if a snapshot already exists there, and starts a background
+// goroutine that flushes changes to path every flushInterval. Call
+// Close to stop the goroutine and flush any pending writes.
+func NewCacheWithPersistence(path string, flushInterval time.Duration, driverURL string, defaultTTL time.Duration) (*persistentCache, error) {
+	cache, err := NewCache(driverURL, defaultTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, ok := cache.(snapshottable)
+	if !ok {
+		return nil, fmt.Errorf("driver %q does not support persistence", driverURL)
+	}
+
+	pc := &persistentCache{
+		CacheInterface: cache,
+		driver:         driver,
+		path:           path,
+		flushInterval:  flushInterval,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	if err := pc.load(); err != nil {
+		return nil, err
+	}
+
+	go pc.flushLoop()
+
+	return pc, nil
+}
+
+// Set stores value and marks the cache dirty so it is written through on
+// the next flush.
+func (p *persistentCache) Set(key string, value interface{}, ttl ...time.Duration) error {
+	if err := p.CacheInterface.Set(key, value, ttl...); err != nil {
+		return err
+	}
+	p.markDirty()
+	return nil
+}
+
+// Del removes key and marks the cache dirty so it is written through on
+// the next flush.
+func (p *persistentCache) Del(key string) error {
+	if err := p.CacheInterface.Del(key); err != nil {
+		return err
+	}
+	p.markDirty()
+	return nil
+}
+
+// MSet stores entries and marks the cache dirty so they are written
+// through on the next flush.
+func (p *persistentCache) MSet(entries map[string]interface{}, ttl ...time.Duration) error {
+	if err := p.CacheInterface.MSet(entries, ttl...); err != nil {
+		return err
+	}
+	p.markDirty()
+	return nil
+}
+
+// CAS stores newValue and marks the cache dirty so it is written through
+// on the next flush.
+func (p *persistentCache) CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error {
+	if err := p.CacheInterface.CAS(key, prevValue, newValue, ttl...); err != nil {
+		return err
+	}
+	p.markDirty()
+	return nil
+}
+
+func (p *persistentCache) markDirty() {
+	p.mutex.Lock()
+	p.dirty = true
+	p.mutex.Unlock()
+}
+
+func (p *persistentCache) flushLoop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.flushIfDirty(); err != nil {
+				fmt.Fprintf(os.Stderr, "cache: flush %s: %v\n", p.path, err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *persistentCache) flushIfDirty() error {
+	p.mutex.Lock()
+	dirty := p.dirty
+	p.dirty = false
+	p.mutex.Unlock()
+
+	if !dirty {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *persistentCache) flush() error {
+	entries := p.driver.snapshotEntries()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return os.Rename(tmp, p.path)
+}
+
+func (p *persistentCache) load() error {
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read snapshot %s: %w", p.path, err)
+	}
+
+	var entries []persistedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("unmarshal snapshot %s: %w", p.path, err)
+	}
+
+	now := time.Now()
+	live := entries[:0]
+	for _, entry := range entries {
+		if !entry.Permanent && entry.Expiry.Before(now) {
+			continue
+		}
+		live = append(live, entry)
+	}
+
+	p.driver.loadEntries(live)
+	return nil
+}
+
+// Close stops the background flush goroutine, writes a final snapshot so
+// no pending changes are lost, and stops the underlying driver. It is
+// safe to call more than once.
+func (p *persistentCache) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.closeErr = p.flush()
+		p.CacheInterface.Stop()
+	})
+	return p.closeErr
+}
+
+// Stop implements CacheInterface.Stop for callers that only hold this
+// cache as a CacheInterface and so cannot see Close's error return (e.g.
+// Server.cache). It performs the same final-flush-and-shutdown as Close,
+// logging any flush error instead of discarding it.
+func (p *persistentCache) Stop() {
+	if err := p.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "cache: close %s: %v\n", p.path, err)
+	}
+}
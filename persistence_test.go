@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistentCacheCASMarksDirty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	pc, err := NewCacheWithPersistence(path, time.Hour, "memory://", time.Minute)
+	if err != nil {
+		t.Fatalf("NewCacheWithPersistence: %v", err)
+	}
+	defer pc.Close()
+
+	if err := pc.CAS("a", nil, "v1"); err != nil {
+		t.Fatalf("CAS: %v", err)
+	}
+
+	pc.mutex.Lock()
+	dirty := pc.dirty
+	pc.mutex.Unlock()
+	if !dirty {
+		t.Fatalf("CAS should mark the cache dirty so a crash before Close doesn't lose the write")
+	}
+
+	if err := pc.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	var entries []persistedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "a" || entries[0].Value != "v1" {
+		t.Fatalf("snapshot entries = %+v, want [{a v1}]", entries)
+	}
+}
+
+func TestNewCacheWithPersistenceReloadsAndDropsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	now := time.Now()
+	seed := []persistedEntry{
+		{Key: "live", Value: "v1", Expiry: now.Add(time.Hour)},
+		{Key: "forever", Value: "v2", Permanent: true},
+		{Key: "stale", Value: "v3", Expiry: now.Add(-time.Hour)},
+	}
+	data, err := json.Marshal(seed)
+	if err != nil {
+		t.Fatalf("marshal seed snapshot: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write seed snapshot: %v", err)
+	}
+
+	pc, err := NewCacheWithPersistence(path, time.Hour, "memory://", time.Minute)
+	if err != nil {
+		t.Fatalf("NewCacheWithPersistence: %v", err)
+	}
+	defer pc.Close()
+
+	if !pc.Has("live") || !pc.Has("forever") {
+		t.Fatalf("non-expired and permanent entries should survive reload")
+	}
+	if pc.Has("stale") {
+		t.Fatalf("expired entry should have been dropped on reload")
+	}
+	if got := pc.Len(); got != 2 {
+		t.Fatalf("Len() after reload = %d, want 2", got)
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisDriver stores entries in a Redis server, reached over a single
+// persistent connection guarded by a mutex. Values are gob-encoded at the
+// driver boundary since Redis only understands bytes.
+type redisDriver struct {
+	mutex      sync.Mutex
+	conn       net.Conn
+	reader     *bufio.Reader
+	defaultTTL time.Duration
+}
+
+// newRedisDriver dials the Redis instance described by u, e.g.
+// redis://host:6379/0.
+func newRedisDriver(u *url.URL, defaultTTL time.Duration) (*redisDriver, error) {
+	addr := u.Host
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial redis at %s: %w", addr, err)
+	}
+
+	d := &redisDriver{
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		defaultTTL: defaultTTL,
+	}
+
+	db := strings.TrimPrefix(u.Path, "/")
+	if db != "" && db != "0" {
+		if _, err := d.command("SELECT", db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("select redis db %s: %w", db, err)
+		}
+	}
+
+	return d, nil
+}
+
+func (d *redisDriver) Has(key string) bool {
+	reply, err := d.command("EXISTS", key)
+	if err != nil {
+		return false
+	}
+	n, _ := reply.(int64)
+	return n > 0
+}
+
+func (d *redisDriver) Set(key string, value interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+
+	if expiry > 0 {
+		_, err = d.command("SET", key, string(encoded), "PX", strconv.FormatInt(expiry.Milliseconds(), 10))
+	} else {
+		_, err = d.command("SET", key, string(encoded))
+	}
+	return err
+}
+
+func (d *redisDriver) Get(key string, dst ...interface{}) (interface{}, error) {
+	reply, err := d.command("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, errKeyNotFound(key)
+	}
+
+	data, ok := reply.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis reply type %T for GET", reply)
+	}
+
+	var outDst interface{}
+	if len(dst) > 0 {
+		outDst = dst[0]
+	}
+	return decodeValue([]byte(data), outDst)
+}
+
+func (d *redisDriver) Del(key string) error {
+	reply, err := d.command("DEL", key)
+	if err != nil {
+		return err
+	}
+	if n, _ := reply.(int64); n == 0 {
+		return errKeyNotFound(key)
+	}
+	return nil
+}
+
+func (d *redisDriver) Keys() []string {
+	reply, err := d.command("KEYS", "*")
+	if err != nil {
+		return nil
+	}
+	items, _ := reply.([]interface{})
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			keys = append(keys, s)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of keys in the selected Redis database.
+func (d *redisDriver) Len() int {
+	reply, err := d.command("DBSIZE")
+	if err != nil {
+		return 0
+	}
+	n, _ := reply.(int64)
+	return int(n)
+}
+
+// Stats always returns zeros: hit/miss/eviction counters belong to the
+// Redis server itself (see INFO stats), not to this driver.
+func (d *redisDriver) Stats() (hits, misses, evictions uint64) {
+	return 0, 0, 0
+}
+
+// Stop closes the connection to Redis. Eviction and expiry are handled
+// server-side, so there is no background sweeper to stop here.
+func (d *redisDriver) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.conn.Close()
+}
+
+// MGet retrieves multiple keys under a single mutex acquisition.
+func (d *redisDriver) MGet(keys []string) map[string]interface{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	result := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		reply, err := d.commandLocked("GET", key)
+		if err != nil || reply == nil {
+			continue
+		}
+		data, ok := reply.(string)
+		if !ok {
+			continue
+		}
+		value, err := decodeValue([]byte(data), nil)
+		if err != nil {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// MSet stores multiple key/value pairs under a single mutex acquisition.
+func (d *redisDriver) MSet(entries map[string]interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, value := range entries {
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return err
+		}
+		if expiry > 0 {
+			_, err = d.commandLocked("SET", key, string(encoded), "PX", strconv.FormatInt(expiry.Milliseconds(), 10))
+		} else {
+			_, err = d.commandLocked("SET", key, string(encoded))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CAS stores newValue under key only if the current value equals
+// prevValue, implemented with Redis's WATCH/MULTI/EXEC optimistic
+// transaction so the check-and-set is atomic despite the round trip.
+func (d *redisDriver) CAS(key string, prevValue, newValue interface{}, ttl ...time.Duration) error {
+	expiry := d.defaultTTL
+	if len(ttl) > 0 {
+		expiry = ttl[0]
+	}
+
+	encodedNew, err := encodeValue(newValue)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, err := d.commandLocked("WATCH", key); err != nil {
+		return err
+	}
+
+	reply, err := d.commandLocked("GET", key)
+	if err != nil {
+		d.commandLocked("UNWATCH")
+		return err
+	}
+
+	var matches bool
+	if reply == nil {
+		matches = prevValue == nil
+	} else {
+		data, _ := reply.(string)
+		current, decErr := decodeValue([]byte(data), nil)
+		if decErr != nil {
+			d.commandLocked("UNWATCH")
+			return decErr
+		}
+		matches = reflect.DeepEqual(current, prevValue)
+	}
+
+	if !matches {
+		d.commandLocked("UNWATCH")
+		return ErrCASMismatch
+	}
+
+	if _, err := d.commandLocked("MULTI"); err != nil {
+		return err
+	}
+
+	if expiry > 0 {
+		_, err = d.commandLocked("SET", key, string(encodedNew), "PX", strconv.FormatInt(expiry.Milliseconds(), 10))
+	} else {
+		_, err = d.commandLocked("SET", key, string(encodedNew))
+	}
+	if err != nil {
+		d.commandLocked("DISCARD")
+		return err
+	}
+
+	execReply, err := d.commandLocked("EXEC")
+	if err != nil {
+		return err
+	}
+	if execReply == nil {
+		return ErrCASMismatch
+	}
+	return nil
+}
+
+// command sends a RESP-encoded command and returns its parsed reply.
+func (d *redisDriver) command(args ...string) (interface{}, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.commandLocked(args...)
+}
+
+// commandLocked is command without acquiring the mutex, so batch
+// operations such as MGet/MSet can issue several commands under a
+// single lock. Callers must hold d.mutex.
+func (d *redisDriver) commandLocked(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := d.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("write redis command: %w", err)
+	}
+
+	return readRESP(d.reader)
+}
+
+// readRESP parses a single RESP reply from r.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read redis reply: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis error: %s", line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return n, err
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("parse bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := readFull(r, buf); err != nil {
+			return nil, fmt.Errorf("read bulk string: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("parse array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			items[i], err = readRESP(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unrecognized redis reply prefix %q", line[0])
+	}
+}
+
+func readFull(r *bufio.Reader, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := r.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}